@@ -13,18 +13,32 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sethvargo/go-githubactions"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 )
 
 const (
@@ -38,44 +52,484 @@ const (
 	useCompleteSourceName    = "useCompleteSourceFilenameAsName"
 	mirrorDirectoryStructure = "mirrorDirectoryStructure"
 	namePrefixInput          = "namePrefix"
+	chunkSizeInput           = "chunkSize"
+	maxRetriesInput          = "maxRetries"
+	recursiveInput           = "recursive"
+	convertToInput           = "convertTo"
+	authTypeInput            = "authType"
+	refreshTokenInput        = "refreshToken"
+	clientIdInput            = "clientId"
+	clientSecretInput        = "clientSecret"
+	driveIdInput             = "driveId"
+	shareWithInput           = "shareWith"
+	shareRoleInput           = "shareRole"
+	shareTypeInput           = "shareType"
+	viewUrlOutput            = "view_url"
+	downloadUrlOutput        = "download_url"
+	skipIfUnchangedInput     = "skipIfUnchanged"
+
+	authTypeServiceAccount   = "service-account"
+	authTypeOAuthToken       = "oauth-token"
+	authTypeWorkloadIdentity = "workload-identity"
+
+	defaultChunkSize  = 8 * 1024 * 1024 // 8 MiB, Drive API's recommended default
+	minChunkSize      = 256 * 1024      // Drive API requires chunk sizes to be a multiple of 256 KiB
+	defaultMaxRetries = 5
+	progressInterval  = 2 * time.Second
+
+	driveUploadBaseURL     = "https://www.googleapis.com/upload/drive/v3/files"
+	statusResumeIncomplete = 308 // Drive's "Resume Incomplete" status for the resumable upload protocol
 )
 
-func uploadToDrive(svc *drive.Service, filename string, folderId string, driveFile *drive.File, name string, mimeType string) (string, error) {
+// retryableUploadError reports whether err represents a transient failure
+// (server error, rate limiting, or a dropped connection mid-upload) that is
+// worth retrying against the same resumable session.
+func retryableUploadError(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	return false
+}
+
+// nextBackoff doubles the given backoff, capped at one minute.
+func nextBackoff(backoff time.Duration) time.Duration {
+	return time.Duration(math.Min(float64(backoff*2), float64(time.Minute)))
+}
+
+// newProgressUpdater returns a googleapi.ProgressUpdater that logs upload
+// progress via githubactions.Infof, throttled so large uploads don't flood
+// the workflow log with a line per chunk.
+func newProgressUpdater(filename string) googleapi.ProgressUpdater {
+	var last time.Time
+	return func(current, total int64) {
+		if time.Since(last) < progressInterval && current < total {
+			return
+		}
+		last = time.Now()
+		percent := float64(100)
+		if total > 0 {
+			percent = float64(current) / float64(total) * 100
+		}
+		githubactions.Infof("progress: %s: %.0f%% (%d/%d bytes)", filename, percent, current, total)
+	}
+}
+
+// sessionFilePath returns the path of the temp file used to persist the
+// resumable upload session URI for a given upload, keyed by the file's
+// content hash together with its exact destination (folder, name, existing
+// file id and target mime type) so re-running the workflow only resumes a
+// session that was opened against that same target, never a different one.
+func sessionFilePath(fileHash string, folderId string, name string, existingFileId string, driveMimeType string) string {
+	key := md5.Sum([]byte(strings.Join([]string{fileHash, folderId, name, existingFileId, driveMimeType}, "|")))
+	return filepath.Join(os.TempDir(), fmt.Sprintf("gdrive-upload-session-%s", hex.EncodeToString(key[:])))
+}
+
+// convertExtensionTargets maps a source file extension to the convertTo
+// value it's compatible with, mirroring the extension table rclone's drive
+// backend uses for Google Workspace conversion.
+var convertExtensionTargets = map[string]string{
+	".docx": "document",
+	".xlsx": "spreadsheet",
+	".pptx": "presentation",
+	".csv":  "spreadsheet",
+	".txt":  "document",
+	".md":   "document",
+	".svg":  "drawing",
+}
+
+var convertTargetMimeTypes = map[string]string{
+	"document":     "application/vnd.google-apps.document",
+	"spreadsheet":  "application/vnd.google-apps.spreadsheet",
+	"presentation": "application/vnd.google-apps.presentation",
+	"drawing":      "application/vnd.google-apps.drawing",
+}
+
+// convertTargetMimeType validates that filename's extension is compatible
+// with convertTo and returns the application/vnd.google-apps.* MIME type to
+// set on the Drive file so the upload is converted to a native Workspace
+// document instead of stored as a blob.
+func convertTargetMimeType(filename string, convertTo string) (string, error) {
+	targetMime, ok := convertTargetMimeTypes[convertTo]
+	if !ok {
+		return "", fmt.Errorf("unsupported convertTo value: %s", convertTo)
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	compatibleTarget, ok := convertExtensionTargets[ext]
+	if !ok || compatibleTarget != convertTo {
+		return "", fmt.Errorf("file extension %s is not compatible with convertTo: %s", ext, convertTo)
+	}
+	return targetMime, nil
+}
+
+// fileUnchanged reports whether the local file's MD5 (already computed by
+// the caller) matches the existing Drive file's md5Checksum, so an upload
+// can be skipped entirely.
+func fileUnchanged(fileHash string, driveFile *drive.File) bool {
+	if driveFile == nil || driveFile.Md5Checksum == "" {
+		return false
+	}
+	return fileHash == driveFile.Md5Checksum
+}
+
+func md5Hash(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", fmt.Errorf("opening file with filename: %v failed with error: %v", filename, err)
+	}
+	defer file.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("hashing file with filename: %v failed with error: %v", filename, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// initiateResumableSession starts a new Drive resumable upload session for
+// filename's metadata and returns the session URI from the response's
+// Location header, per
+// https://developers.google.com/drive/api/guides/manage-uploads#start-resumable.
+func initiateResumableSession(client *http.Client, existingFileId string, folderId string, name string, mimeType string) (string, error) {
+	metadata := map[string]interface{}{
+		"name":     name,
+		"mimeType": mimeType,
+	}
+
+	var method, uri string
+	if existingFileId != "" {
+		method = http.MethodPatch
+		uri = fmt.Sprintf("%s/%s?uploadType=resumable&supportsAllDrives=true&addParents=%s", driveUploadBaseURL, url.PathEscape(existingFileId), url.QueryEscape(folderId))
+	} else {
+		method = http.MethodPost
+		metadata["parents"] = []string{folderId}
+		uri = fmt.Sprintf("%s?uploadType=resumable&supportsAllDrives=true", driveUploadBaseURL)
+	}
+
+	body, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(method, uri, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", &googleapi.Error{Code: resp.StatusCode, Body: string(respBody), Message: fmt.Sprintf("initiating resumable session failed with status %d", resp.StatusCode)}
+	}
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", errors.New("resumable session response missing Location header")
+	}
+	return location, nil
+}
+
+// resumeOffset queries a resumable session's progress with a status-check
+// request (an empty PUT with an open-ended Content-Range) and returns the
+// byte offset to resume from, or the completed file if Drive already holds
+// every byte.
+func resumeOffset(client *http.Client, sessionURI string, size int64) (int64, *drive.File, error) {
+	req, err := http.NewRequest(http.MethodPut, sessionURI, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.ContentLength = 0
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var f drive.File
+		if err := json.Unmarshal(body, &f); err != nil {
+			return 0, nil, fmt.Errorf("parsing completed upload response failed: %v", err)
+		}
+		return size, &f, nil
+	case statusResumeIncomplete:
+		rangeHeader := resp.Header.Get("Range")
+		if rangeHeader == "" {
+			return 0, nil, nil
+		}
+		var start, end int64
+		if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end); err != nil {
+			return 0, nil, nil
+		}
+		return end + 1, nil, nil
+	default:
+		return 0, nil, &googleapi.Error{Code: resp.StatusCode, Body: string(body), Message: fmt.Sprintf("checking resumable session status failed with status %d", resp.StatusCode)}
+	}
+}
+
+// uploadResumable sends file's remaining bytes, starting at startOffset, to
+// an established resumable session in chunkSize pieces, reporting progress
+// via progress and returning the Drive file once the upload is acknowledged
+// complete.
+func uploadResumable(client *http.Client, sessionURI string, file *os.File, size int64, chunkSize int64, startOffset int64, progress googleapi.ProgressUpdater) (*drive.File, error) {
+	if size == 0 {
+		req, err := http.NewRequest(http.MethodPut, sessionURI, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.ContentLength = 0
+		req.Header.Set("Content-Range", "bytes */0")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+			return nil, &googleapi.Error{Code: resp.StatusCode, Body: string(body), Message: fmt.Sprintf("uploading empty file failed with status %d", resp.StatusCode)}
+		}
+		var f drive.File
+		if err := json.Unmarshal(body, &f); err != nil {
+			return nil, fmt.Errorf("parsing completed upload response failed: %v", err)
+		}
+		progress(size, size)
+		return &f, nil
+	}
+
+	offset := startOffset
+	for offset < size {
+		end := offset + chunkSize
+		if end > size {
+			end = size
+		}
+
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequest(http.MethodPut, sessionURI, io.LimitReader(file, end-offset))
+		if err != nil {
+			return nil, err
+		}
+		req.ContentLength = end - offset
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, end-1, size))
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK, http.StatusCreated:
+			var f drive.File
+			if err := json.Unmarshal(body, &f); err != nil {
+				return nil, fmt.Errorf("parsing completed upload response failed: %v", err)
+			}
+			progress(size, size)
+			return &f, nil
+		case statusResumeIncomplete:
+			offset = end
+			progress(offset, size)
+		default:
+			return nil, &googleapi.Error{Code: resp.StatusCode, Body: string(body), Message: fmt.Sprintf("uploading chunk failed with status %d", resp.StatusCode)}
+		}
+	}
+	return nil, errors.New("resumable upload ended without a completion response from Drive")
+}
+
+func uploadToDrive(svc *drive.Service, client *http.Client, filename string, folderId string, driveFile *drive.File, name string, mimeType string, chunkSize int64, maxRetries int, convertTo string, fileHash string) (string, *drive.File, error) {
 	fi, err := os.Lstat(filename)
 	if err != nil {
-		return "", fmt.Errorf("lstat of file with filename: %v failed with error: %v", filename, err)
+		return "", nil, fmt.Errorf("lstat of file with filename: %v failed with error: %v", filename, err)
 	}
 	if fi.IsDir() {
 		fmt.Printf("%s is a directory. skipping upload.", filename)
-		return "", nil
+		return "", nil, nil
 	}
-	file, err := os.Open(filename)
-	if err != nil {
-		return "", fmt.Errorf("opening file with filename: %v failed with error: %v", filename, err)
+
+	driveMimeType := mimeType
+	if convertTo != "" {
+		driveMimeType, err = convertTargetMimeType(filename, convertTo)
+		if err != nil {
+			return "", nil, err
+		}
 	}
 
-	var updatedFile *drive.File
+	existingFileId := ""
 	if driveFile != nil {
-		f := &drive.File{
-			Name:     name,
-			MimeType: mimeType,
+		existingFileId = driveFile.Id
+	}
+
+	sessionFile := sessionFilePath(fileHash, folderId, name, existingFileId, driveMimeType)
+	sessionURI := ""
+	if cached, readErr := os.ReadFile(sessionFile); readErr == nil && len(cached) > 0 {
+		sessionURI = strings.TrimSpace(string(cached))
+		githubactions.Infof("resuming previous upload session for %s", filename)
+	}
+
+	progress := newProgressUpdater(filename)
+
+	var updatedFile *drive.File
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		if sessionURI == "" {
+			uri, err := initiateResumableSession(client, existingFileId, folderId, name, driveMimeType)
+			if err != nil {
+				if !retryableUploadError(err) || attempt >= maxRetries {
+					return "", nil, fmt.Errorf("initiating resumable upload session failed with error: %v", err)
+				}
+				githubactions.Warningf("initiating upload session for %s failed (attempt %d/%d): %v", filename, attempt+1, maxRetries, err)
+				time.Sleep(backoff)
+				backoff = nextBackoff(backoff)
+				continue
+			}
+			sessionURI = uri
 		}
-		updatedFile, err = svc.Files.Update(driveFile.Id, f).AddParents(folderId).Media(file).SupportsAllDrives(true).Do()
-	} else {
-		f := &drive.File{
-			Name:     name,
-			MimeType: mimeType,
-			Parents:  []string{folderId},
+
+		offset, completed, err := resumeOffset(client, sessionURI, fi.Size())
+		if err != nil {
+			if attempt >= maxRetries {
+				return "", nil, fmt.Errorf("resuming upload session failed with error: %v", err)
+			}
+			githubactions.Warningf("resumable session for %s is no longer valid, starting a new session (attempt %d/%d): %v", filename, attempt+1, maxRetries, err)
+			sessionURI = ""
+			time.Sleep(backoff)
+			backoff = nextBackoff(backoff)
+			continue
+		}
+		if completed != nil {
+			updatedFile = completed
+			break
+		}
+
+		file, err := os.Open(filename)
+		if err != nil {
+			return "", nil, fmt.Errorf("opening file with filename: %v failed with error: %v", filename, err)
+		}
+		result, uploadErr := uploadResumable(client, sessionURI, file, fi.Size(), chunkSize, offset, progress)
+		file.Close()
+
+		if uploadErr == nil {
+			updatedFile = result
+			break
+		}
+		if !retryableUploadError(uploadErr) || attempt >= maxRetries {
+			return "", nil, fmt.Errorf("creating/updating file failed with error: %v", uploadErr)
 		}
-		updatedFile, err = svc.Files.Create(f).Media(file).SupportsAllDrives(true).Do()
+
+		githubactions.Warningf("upload of %s failed with retryable error (attempt %d/%d): %v", filename, attempt+1, maxRetries, uploadErr)
+		_ = os.WriteFile(sessionFile, []byte(sessionURI), 0600)
+		time.Sleep(backoff)
+		backoff = nextBackoff(backoff)
 	}
 
-	if err != nil {
-		return "", fmt.Errorf("creating/updating file failed with error: %v", err)
+	os.Remove(sessionFile)
+
+	if convertTo == "" {
+		var uploaded *drive.File
+		verifyBackoff := time.Second
+		for attempt := 0; ; attempt++ {
+			uploaded, err = svc.Files.Get(updatedFile.Id).Fields("md5Checksum").SupportsAllDrives(true).Do()
+			if err == nil || !retryableUploadError(err) || attempt >= maxRetries {
+				break
+			}
+			time.Sleep(verifyBackoff)
+			verifyBackoff = nextBackoff(verifyBackoff)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("verifying uploaded file failed with error: %v", err)
+		}
+		if uploaded.Md5Checksum != "" && uploaded.Md5Checksum != fileHash {
+			return "", nil, fmt.Errorf("uploaded file's md5Checksum (%s) does not match local file's md5 (%s)", uploaded.Md5Checksum, fileHash)
+		}
 	}
 
 	link := fmt.Sprintf("https://drive.google.com/file/d/%s/view", updatedFile.Id)
-	return link, nil
+	return link, updatedFile, nil
+}
+
+// shareFile grants the requested permission(s) on fileId. shareWith is either
+// the literal value "anyone" or a comma-separated list of emails (domain
+// names when shareType is domain), shared using shareRole and shareType
+// (user/group/domain/anyone).
+func shareFile(svc *drive.Service, fileId string, shareWith string, shareRole string, shareType string) error {
+	if shareWith == "" {
+		return nil
+	}
+
+	recipients := []string{shareWith}
+	if shareType != "anyone" {
+		recipients = strings.Split(shareWith, ",")
+	}
+
+	for _, recipient := range recipients {
+		permission := &drive.Permission{
+			Role: shareRole,
+			Type: shareType,
+		}
+		if shareType == "domain" {
+			permission.Domain = strings.TrimSpace(recipient)
+		} else if shareType != "anyone" {
+			permission.EmailAddress = strings.TrimSpace(recipient)
+		}
+
+		if _, err := svc.Permissions.Create(fileId, permission).SupportsAllDrives(true).Do(); err != nil {
+			return fmt.Errorf("failed to create %s permission on file: %v", shareType, err)
+		}
+	}
+	return nil
+}
+
+// applySharingAndOutputs shares fileId per shareWith/shareRole/shareType and,
+// when sharing publicly, fetches and sets the view_url/download_url outputs.
+// Applied on every upload, including ones skipped via skipIfUnchanged, so
+// downstream steps see consistent outputs regardless of whether the upload
+// was skipped.
+func applySharingAndOutputs(svc *drive.Service, fileId string, shareWith string, shareRole string, shareType string) error {
+	if err := shareFile(svc, fileId, shareWith, shareRole, shareType); err != nil {
+		return err
+	}
+	if shareType != "anyone" {
+		return nil
+	}
+
+	sharedFile, err := svc.Files.Get(fileId).Fields("webViewLink, webContentLink").SupportsAllDrives(true).Do()
+	if err != nil {
+		return fmt.Errorf("failed to fetch shareable links: %v", err)
+	}
+	githubactions.SetOutput(viewUrlOutput, sharedFile.WebViewLink)
+	githubactions.SetOutput(downloadUrlOutput, sharedFile.WebContentLink)
+	return nil
 }
 
 func main() {
@@ -117,29 +571,65 @@ func main() {
 	// get optional flags
 	useCompleteSourceNameFlag, _ := strconv.ParseBool(githubactions.GetInput(useCompleteSourceName))
 	mirrorDirectoryStructureFlag, _ := strconv.ParseBool(githubactions.GetInput(mirrorDirectoryStructure))
+	recursiveFlag, _ := strconv.ParseBool(githubactions.GetInput(recursiveInput))
+	skipIfUnchangedFlag, _ := strconv.ParseBool(githubactions.GetInput(skipIfUnchangedInput))
 	namePrefix := githubactions.GetInput(namePrefixInput)
 
-	// get credentials from action input
-	credentials := githubactions.GetInput(credentialsInput)
-	if credentials == "" {
-		missingInput(credentialsInput)
+	// get convertTo argument from action input
+	convertTo := githubactions.GetInput(convertToInput)
+	if convertTo != "" {
+		if _, ok := convertTargetMimeTypes[convertTo]; !ok {
+			githubactions.Fatalf(fmt.Sprintf("Invalid convertTo: %s must be one of document, spreadsheet, presentation, drawing", convertTo))
+		}
 	}
 
-	// decode credentials from base64
-	decodedCreds, err := base64.StdEncoding.DecodeString(credentials)
-	if err != nil {
-		githubactions.Fatalf(fmt.Sprintf("Failed to decode credentials: %v", err))
+	// get chunkSize argument from action input, must be a multiple of 256 KiB per the Drive API
+	chunkSize := int64(defaultChunkSize)
+	if rawChunkSize := githubactions.GetInput(chunkSizeInput); rawChunkSize != "" {
+		parsedChunkSize, err := strconv.ParseInt(rawChunkSize, 10, 64)
+		if err != nil || parsedChunkSize <= 0 || parsedChunkSize%minChunkSize != 0 {
+			githubactions.Fatalf(fmt.Sprintf("Invalid chunkSize: %s must be a positive multiple of %d bytes", rawChunkSize, minChunkSize))
+		}
+		chunkSize = parsedChunkSize
 	}
 
-	// create a JWT config from the credentials
-	jwtConfig, err := google.JWTConfigFromJSON(decodedCreds, scope)
-	if err != nil {
-		githubactions.Fatalf(fmt.Sprintf("Failed to create JWT config: %v", err))
+	// get maxRetries argument from action input
+	maxRetries := defaultMaxRetries
+	if rawMaxRetries := githubactions.GetInput(maxRetriesInput); rawMaxRetries != "" {
+		parsedMaxRetries, err := strconv.Atoi(rawMaxRetries)
+		if err != nil || parsedMaxRetries < 0 {
+			githubactions.Fatalf(fmt.Sprintf("Invalid maxRetries: %s must be a non-negative integer", rawMaxRetries))
+		}
+		maxRetries = parsedMaxRetries
+	}
+
+	// get authType argument from action input, defaulting to the service account flow
+	authType := githubactions.GetInput(authTypeInput)
+
+	// get driveId argument from action input, for searching/uploading to a shared drive
+	driveId := githubactions.GetInput(driveIdInput)
+
+	// get sharing arguments from action input
+	shareWith := githubactions.GetInput(shareWithInput)
+	shareRole := githubactions.GetInput(shareRoleInput)
+	if shareRole == "" {
+		shareRole = "reader"
+	}
+	shareType := githubactions.GetInput(shareTypeInput)
+	if shareType == "" {
+		if shareWith == "anyone" {
+			shareType = "anyone"
+		} else {
+			shareType = "user"
+		}
 	}
 
 	// create a context and client for Google Drive API
 	ctx := context.Background()
-	client := jwtConfig.Client(ctx)
+	client, err := buildHTTPClient(ctx, authType)
+	if err != nil {
+		githubactions.Fatalf(fmt.Sprintf("Failed to authenticate: %v", err))
+	}
 
 	// create a new drive service client
 	svc, err := drive.NewService(ctx, drive.WithHTTPClient(client))
@@ -147,16 +637,33 @@ func main() {
 		githubactions.Fatalf(fmt.Sprintf("Failed to create Drive service client: %v", err))
 	}
 
+	// memoizes Drive folder IDs created for directory paths across files
+	folderCache := make(map[string]string)
+
 	// iterate over files matching the pattern
 	for _, file := range files {
-		// handle file names with spaces
-		escapedName := strings.Replace(file, " ", "\\ ", -1)
+		// recurse into directories, mirroring the subtree into Drive, when recursive is enabled
+		fi, err := os.Lstat(file)
+		if err != nil {
+			githubactions.Fatalf(fmt.Sprintf("lstat of file with filename: %v failed with error: %v", file, err))
+		}
+		if fi.IsDir() {
+			if !recursiveFlag {
+				fmt.Printf("%s is a directory. skipping upload.", file)
+				continue
+			}
+			if err := uploadDirectory(svc, client, file, folderId, mimeType, chunkSize, maxRetries, convertTo, driveId, shareWith, shareRole, shareType, skipIfUnchangedFlag, folderCache); err != nil {
+				githubactions.Fatalf(fmt.Sprintf("Failed to upload directory to Google Drive: %v", err))
+			}
+			continue
+		}
 
 		// create directory structure if mirrorDirectoryStructure flag is enabled
+		uploadFolderId := folderId
 		if mirrorDirectoryStructureFlag {
-			fileDir := filepath.Dir(escapedName)
+			fileDir := filepath.Dir(file)
 			if fileDir != "." {
-				_, err = createDriveDirectory(svc, folderId, fileDir)
+				uploadFolderId, err = getOrCreateFolderPath(svc, folderId, fileDir, folderCache, driveId)
 				if err != nil {
 					githubactions.Fatalf(fmt.Sprintf("Failed to create directory structure on Google Drive: %v", err))
 				}
@@ -179,24 +686,46 @@ func main() {
 		}
 
 		// check if the file already exists in the folder
-		driveFile, err := findFileByName(svc, uploadedFileName, folderId)
+		driveFile, err := findFileByName(svc, uploadedFileName, uploadFolderId, driveId)
 		if err != nil {
 			githubactions.Fatalf(fmt.Sprintf("Failed to check existing files in the folder: %v", err))
 		}
 
+		// hash the file once and reuse it for the skip-check, the upload session key, and post-upload verification
+		fileHash, err := md5Hash(file)
+		if err != nil {
+			githubactions.Fatalf(fmt.Sprintf("Failed to compute md5 for file: %v", err))
+		}
+
+		// skip the upload entirely if the remote copy already matches the local file
+		if skipIfUnchangedFlag && fileUnchanged(fileHash, driveFile) {
+			if err := applySharingAndOutputs(svc, driveFile.Id, shareWith, shareRole, shareType); err != nil {
+				githubactions.Fatalf(fmt.Sprintf("Failed to share file: %v", err))
+			}
+			githubactions.Infof("Uploaded file: %s", fmt.Sprintf("https://drive.google.com/file/d/%s/view", driveFile.Id))
+			continue
+		}
+
 		// upload the file to Google Drive
-		uploadedLink, err := uploadToDrive(svc, file, folderId, driveFile, uploadedFileName, mimeType)
+		uploadedLink, uploadedFile, err := uploadToDrive(svc, client, file, uploadFolderId, driveFile, uploadedFileName, mimeType, chunkSize, maxRetries, convertTo, fileHash)
 		if err != nil {
 			githubactions.Fatalf(fmt.Sprintf("Failed to upload file to Google Drive: %v", err))
 		}
 
+		// grant sharing permissions and surface shareable links if requested
+		if uploadedFile != nil {
+			if err := applySharingAndOutputs(svc, uploadedFile.Id, shareWith, shareRole, shareType); err != nil {
+				githubactions.Fatalf(fmt.Sprintf("Failed to share file: %v", err))
+			}
+		}
+
 		// print the link to the uploaded file
 		githubactions.Infof("Uploaded file: %s", uploadedLink)
 	}
 }
 
-func createDriveDirectory(svc *drive.Service, parentFolderID, folderName string) (string, error) {
-	folder, err := findFileByName(svc, folderName, parentFolderID)
+func createDriveDirectory(svc *drive.Service, parentFolderID, folderName string, driveId string) (string, error) {
+	folder, err := findFileByName(svc, folderName, parentFolderID, driveId)
 	if err != nil {
 		return "", fmt.Errorf("failed to check existing folders in the parent folder: %v", err)
 	}
@@ -218,9 +747,101 @@ func createDriveDirectory(svc *drive.Service, parentFolderID, folderName string)
 	return newFolder.Id, nil
 }
 
-func findFileByName(svc *drive.Service, name, parentFolderID string) (*drive.File, error) {
+// getOrCreateFolderPath walks relPath segment by segment under rootID,
+// creating any missing intermediate folders via createDriveDirectory and
+// memoizing each segment's Drive folder ID in cache so repeated calls for
+// sibling files avoid redundant Files.List lookups.
+func getOrCreateFolderPath(svc *drive.Service, rootID string, relPath string, cache map[string]string, driveId string) (string, error) {
+	if relPath == "" || relPath == "." {
+		return rootID, nil
+	}
+
+	parentID := rootID
+	cacheKey := rootID
+	for _, segment := range strings.Split(filepath.ToSlash(relPath), "/") {
+		if segment == "" {
+			continue
+		}
+		cacheKey = cacheKey + "/" + segment
+		if id, ok := cache[cacheKey]; ok {
+			parentID = id
+			continue
+		}
+
+		folderID, err := createDriveDirectory(svc, parentID, segment, driveId)
+		if err != nil {
+			return "", err
+		}
+		cache[cacheKey] = folderID
+		parentID = folderID
+	}
+	return parentID, nil
+}
+
+// uploadDirectory walks root and mirrors its subtree into Drive under
+// rootFolderId, creating intermediate folders on demand and uploading each
+// entry it encounters that is not a directory.
+func uploadDirectory(svc *drive.Service, client *http.Client, root string, rootFolderId string, mimeType string, chunkSize int64, maxRetries int, convertTo string, driveId string, shareWith string, shareRole string, shareType string, skipIfUnchanged bool, folderCache map[string]string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("computing relative path for %v failed with error: %v", path, err)
+		}
+
+		targetFolderId, err := getOrCreateFolderPath(svc, rootFolderId, filepath.Dir(relPath), folderCache, driveId)
+		if err != nil {
+			return fmt.Errorf("failed to create directory structure on Google Drive: %v", err)
+		}
+
+		uploadedFileName := filepath.Base(path)
+		driveFile, err := findFileByName(svc, uploadedFileName, targetFolderId, driveId)
+		if err != nil {
+			return fmt.Errorf("failed to check existing files in the folder: %v", err)
+		}
+
+		fileHash, err := md5Hash(path)
+		if err != nil {
+			return fmt.Errorf("failed to compute md5 for file: %v", err)
+		}
+
+		if skipIfUnchanged && fileUnchanged(fileHash, driveFile) {
+			if err := applySharingAndOutputs(svc, driveFile.Id, shareWith, shareRole, shareType); err != nil {
+				return fmt.Errorf("failed to share file: %v", err)
+			}
+			githubactions.Infof("Uploaded file: %s", fmt.Sprintf("https://drive.google.com/file/d/%s/view", driveFile.Id))
+			return nil
+		}
+
+		uploadedLink, uploadedFile, err := uploadToDrive(svc, client, path, targetFolderId, driveFile, uploadedFileName, mimeType, chunkSize, maxRetries, convertTo, fileHash)
+		if err != nil {
+			return fmt.Errorf("failed to upload file to Google Drive: %v", err)
+		}
+
+		if uploadedFile != nil {
+			if err := applySharingAndOutputs(svc, uploadedFile.Id, shareWith, shareRole, shareType); err != nil {
+				return fmt.Errorf("failed to share file: %v", err)
+			}
+		}
+
+		githubactions.Infof("Uploaded file: %s", uploadedLink)
+		return nil
+	})
+}
+
+func findFileByName(svc *drive.Service, name, parentFolderID string, driveId string) (*drive.File, error) {
 	query := fmt.Sprintf("name = '%s' and '%s' in parents and trashed = false", name, parentFolderID)
-	files, err := svc.Files.List().Q(query).Do()
+	call := svc.Files.List().Q(query).Fields("files(id, name, md5Checksum, size, modifiedTime)")
+	if driveId != "" {
+		call = call.Corpora("drive").DriveId(driveId).IncludeItemsFromAllDrives(true).SupportsAllDrives(true)
+	}
+	files, err := call.Do()
 	if err != nil {
 		return nil, fmt.Errorf("error searching for file by name: %v", err)
 	}
@@ -230,6 +851,63 @@ func findFileByName(svc *drive.Service, name, parentFolderID string) (*drive.Fil
 	return nil, nil
 }
 
+// buildHTTPClient authenticates to Google Drive using the mode selected by
+// authType: a base64-encoded service account key (the default), a refresh
+// token for a personal Drive, or workload identity federation (e.g. GitHub
+// OIDC) via the environment's default credentials.
+func buildHTTPClient(ctx context.Context, authType string) (*http.Client, error) {
+	switch authType {
+	case "", authTypeServiceAccount:
+		credentials := githubactions.GetInput(credentialsInput)
+		if credentials == "" {
+			missingInput(credentialsInput)
+		}
+
+		decodedCreds, err := base64.StdEncoding.DecodeString(credentials)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode credentials: %v", err)
+		}
+
+		jwtConfig, err := google.JWTConfigFromJSON(decodedCreds, scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create JWT config: %v", err)
+		}
+		return jwtConfig.Client(ctx), nil
+
+	case authTypeOAuthToken:
+		refreshToken := githubactions.GetInput(refreshTokenInput)
+		if refreshToken == "" {
+			missingInput(refreshTokenInput)
+		}
+		clientId := githubactions.GetInput(clientIdInput)
+		if clientId == "" {
+			missingInput(clientIdInput)
+		}
+		clientSecret := githubactions.GetInput(clientSecretInput)
+		if clientSecret == "" {
+			missingInput(clientSecretInput)
+		}
+
+		conf := &oauth2.Config{
+			ClientID:     clientId,
+			ClientSecret: clientSecret,
+			Endpoint:     google.Endpoint,
+			Scopes:       []string{scope},
+		}
+		return conf.Client(ctx, &oauth2.Token{RefreshToken: refreshToken}), nil
+
+	case authTypeWorkloadIdentity:
+		creds, err := google.FindDefaultCredentials(ctx, scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find default credentials: %v", err)
+		}
+		return oauth2.NewClient(ctx, creds.TokenSource), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported authType: %s", authType)
+	}
+}
+
 func missingInput(inputName string) {
 	githubactions.Fatalf(fmt.Sprintf("Input %s is missing or empty", inputName))
 }